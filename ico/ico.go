@@ -0,0 +1,349 @@
+// Package ico implements a decoder for the Windows icon (.ico) file format.
+//
+// It supports both classic BITMAPINFOHEADER-based entries and the PNG-compressed
+// entries used by Vista and later for large icon sizes.
+package ico
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", Decode, DecodeConfig)
+}
+
+// DirEntry describes a single ICONDIRENTRY, the per-image metadata stored in
+// an ICO directory. It is returned by ParseIco for inspection without
+// decoding the underlying pixels.
+type DirEntry struct {
+	Width       int
+	Height      int
+	ColorCount  byte
+	Planes      uint16
+	BitCount    uint16
+	BytesInRes  uint32
+	ImageOffset uint32
+}
+
+// ErrInvalidFormat is returned when the input is not a well-formed ICO file.
+var ErrInvalidFormat = errors.New("ico: invalid format")
+
+// ParseIco reads the ICONDIR and ICONDIRENTRY records from r and returns
+// their metadata without decoding any pixel data. It is useful for
+// inspecting an icon (e.g. favicon.ico) without paying the cost of a full
+// decode.
+func ParseIco(r io.Reader) ([]DirEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := parseDir(data)
+	return entries, err
+}
+
+// Decode reads an ICO image from r and returns the largest image it
+// contains, to satisfy the single-image image.Decode contract.
+func Decode(r io.Reader) (image.Image, error) {
+	images, err := DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, ErrInvalidFormat
+	}
+	best := images[0]
+	for _, img := range images[1:] {
+		if area(img.Bounds()) > area(best.Bounds()) {
+			best = img
+		}
+	}
+	return best, nil
+}
+
+// DecodeAll reads an ICO image from r and returns every embedded image in
+// directory order.
+func DecodeAll(r io.Reader) ([]image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := parseDir(data)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]image.Image, 0, len(entries))
+	for _, e := range entries {
+		img, err := decodeEntry(data, e)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// DecodeConfig returns the color model and dimensions of the largest image
+// in the ICO without decoding its pixels.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	entries, _, err := parseDir(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if len(entries) == 0 {
+		return image.Config{}, ErrInvalidFormat
+	}
+	best := entries[0]
+	for _, e := range entries[1:] {
+		if e.Width*e.Height > best.Width*best.Height {
+			best = e
+		}
+	}
+	payload, err := payloadOf(data, best)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if bytes.HasPrefix(payload, pngSignature) {
+		return png.DecodeConfig(bytes.NewReader(payload))
+	}
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      best.Width,
+		Height:     best.Height,
+	}, nil
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+func payloadOf(data []byte, e DirEntry) ([]byte, error) {
+	start := int(e.ImageOffset)
+	end := start + int(e.BytesInRes)
+	if start < 0 || end > len(data) || start > end {
+		return nil, ErrInvalidFormat
+	}
+	return data[start:end], nil
+}
+
+// parseDir parses the ICONDIR header and its ICONDIRENTRY records. It
+// returns the directory entries and the icon type (1 for ICO, 2 for CUR).
+func parseDir(data []byte) ([]DirEntry, uint16, error) {
+	if len(data) < 6 {
+		return nil, 0, ErrInvalidFormat
+	}
+	reserved := le16(data[0:2])
+	iconType := le16(data[2:4])
+	count := int(le16(data[4:6]))
+	if reserved != 0 || (iconType != 1 && iconType != 2) {
+		return nil, 0, ErrInvalidFormat
+	}
+	if len(data) < 6+count*16 {
+		return nil, 0, ErrInvalidFormat
+	}
+	entries := make([]DirEntry, count)
+	for i := 0; i < count; i++ {
+		rec := data[6+i*16 : 6+i*16+16]
+		width := int(rec[0])
+		if width == 0 {
+			width = 256
+		}
+		height := int(rec[1])
+		if height == 0 {
+			height = 256
+		}
+		entries[i] = DirEntry{
+			Width:       width,
+			Height:      height,
+			ColorCount:  rec[2],
+			Planes:      le16(rec[4:6]),
+			BitCount:    le16(rec[6:8]),
+			BytesInRes:  le32(rec[8:12]),
+			ImageOffset: le32(rec[12:16]),
+		}
+	}
+	return entries, iconType, nil
+}
+
+func decodeEntry(data []byte, e DirEntry) (image.Image, error) {
+	payload, err := payloadOf(data, e)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(payload, pngSignature) {
+		return png.Decode(bytes.NewReader(payload))
+	}
+	return decodeDIB(payload, e.Width, e.Height)
+}
+
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+// decodeDIB decodes a BITMAPINFOHEADER-based ICO/CUR entry. The stored DIB
+// height is 2x the icon height: the top half is the XOR (color) image and
+// the bottom half is the 1-bpp AND mask. Classic icons without a real alpha
+// channel get their transparency from the AND mask instead.
+func decodeDIB(payload []byte, iconW, iconH int) (image.Image, error) {
+	if len(payload) < 40 {
+		return nil, ErrInvalidFormat
+	}
+	headerSize := le32(payload[0:4])
+	if headerSize < 40 {
+		return nil, fmt.Errorf("ico: unsupported DIB header size %d", headerSize)
+	}
+	width := int(int32(le32(payload[4:8])))
+	rawHeight := int(int32(le32(payload[8:12])))
+	bitCount := int(le16(payload[14:16]))
+	colorsUsed := int(le32(payload[32:36]))
+
+	height := rawHeight / 2
+	if width != iconW || height != iconH {
+		// Trust the DIB itself; the directory entry is only a hint.
+		iconW, iconH = width, height
+	}
+	if iconW <= 0 || iconH <= 0 {
+		return nil, ErrInvalidFormat
+	}
+
+	offset := int(headerSize)
+	var palette [][4]byte
+	if bitCount <= 8 {
+		n := colorsUsed
+		if n == 0 {
+			n = 1 << uint(bitCount)
+		}
+		palette = make([][4]byte, n)
+		for i := 0; i < n; i++ {
+			if offset+4 > len(payload) {
+				return nil, ErrInvalidFormat
+			}
+			copy(palette[i][:], payload[offset:offset+4])
+			offset += 4
+		}
+	}
+
+	xorRowSize := ((iconW*bitCount + 31) / 32) * 4
+	xorSize := xorRowSize * iconH
+	if xorSize < 0 || offset+xorSize > len(payload) {
+		return nil, ErrInvalidFormat
+	}
+	xor := payload[offset : offset+xorSize]
+	offset += xorSize
+
+	andRowSize := ((iconW + 31) / 32) * 4
+	andSize := andRowSize * iconH
+	if andSize < 0 {
+		return nil, ErrInvalidFormat
+	}
+	var and []byte
+	if offset+andSize <= len(payload) {
+		and = payload[offset : offset+andSize]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, iconW, iconH))
+	hasAlpha := false
+	for y := 0; y < iconH; y++ {
+		srcRow := xor[(iconH-1-y)*xorRowSize : (iconH-1-y)*xorRowSize+xorRowSize]
+		for x := 0; x < iconW; x++ {
+			r, g, b, a, ok := readPixel(srcRow, palette, bitCount, x)
+			if !ok {
+				return nil, ErrInvalidFormat
+			}
+			if bitCount == 32 && a != 0 {
+				hasAlpha = true
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: r, G: g, B: b, A: a})
+		}
+	}
+
+	if bitCount != 32 || !hasAlpha {
+		applyANDMask(img, and, andRowSize, iconW, iconH)
+	}
+	return img, nil
+}
+
+func readPixel(row []byte, palette [][4]byte, bitCount, x int) (r, g, b, a byte, ok bool) {
+	switch bitCount {
+	case 32:
+		i := x * 4
+		if i+4 > len(row) {
+			return 0, 0, 0, 0, false
+		}
+		return row[i+2], row[i+1], row[i], row[i+3], true
+	case 24:
+		i := x * 3
+		if i+3 > len(row) {
+			return 0, 0, 0, 0, false
+		}
+		return row[i+2], row[i+1], row[i], 255, true
+	case 8:
+		if x >= len(row) || int(row[x]) >= len(palette) {
+			return 0, 0, 0, 0, false
+		}
+		p := palette[row[x]]
+		return p[2], p[1], p[0], 255, true
+	case 4:
+		byteIdx := x / 2
+		if byteIdx >= len(row) {
+			return 0, 0, 0, 0, false
+		}
+		var idx byte
+		if x%2 == 0 {
+			idx = row[byteIdx] >> 4
+		} else {
+			idx = row[byteIdx] & 0x0f
+		}
+		if int(idx) >= len(palette) {
+			return 0, 0, 0, 0, false
+		}
+		p := palette[idx]
+		return p[2], p[1], p[0], 255, true
+	case 1:
+		byteIdx := x / 8
+		if byteIdx >= len(row) {
+			return 0, 0, 0, 0, false
+		}
+		bit := (row[byteIdx] >> uint(7-x%8)) & 1
+		if int(bit) >= len(palette) {
+			return 0, 0, 0, 0, false
+		}
+		p := palette[bit]
+		return p[2], p[1], p[0], 255, true
+	default:
+		return 0, 0, 0, 0, false
+	}
+}
+
+func applyANDMask(img *image.NRGBA, and []byte, rowSize, w, h int) {
+	if len(and) < rowSize*h {
+		return
+	}
+	for y := 0; y < h; y++ {
+		row := and[(h-1-y)*rowSize : (h-1-y)*rowSize+rowSize]
+		for x := 0; x < w; x++ {
+			byteIdx := x / 8
+			bit := (row[byteIdx] >> uint(7-x%8)) & 1
+			if bit == 1 {
+				c := img.NRGBAAt(x, y)
+				c.A = 0
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
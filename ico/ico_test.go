@@ -0,0 +1,175 @@
+package ico
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// rawEntry is the inputs needed to build one ICONDIRENTRY plus its payload
+// for buildICO.
+type rawEntry struct {
+	width, height int
+	payload       []byte
+}
+
+// buildICO assembles a minimal ICONDIR (iconType 1) or CURSORDIR (iconType
+// 2) around the given entries, mirroring the on-disk layout decodeDIB and
+// parseDir expect.
+func buildICO(iconType uint16, entries []rawEntry) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, iconType)
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+
+	offset := 6 + 16*len(entries)
+	var dir, data bytes.Buffer
+	for _, e := range entries {
+		dir.WriteByte(byte(e.width))
+		dir.WriteByte(byte(e.height))
+		dir.WriteByte(0)
+		dir.WriteByte(0)
+		binary.Write(&dir, binary.LittleEndian, uint16(1))
+		binary.Write(&dir, binary.LittleEndian, uint16(32))
+		binary.Write(&dir, binary.LittleEndian, uint32(len(e.payload)))
+		binary.Write(&dir, binary.LittleEndian, uint32(offset))
+		data.Write(e.payload)
+		offset += len(e.payload)
+	}
+	buf.Write(dir.Bytes())
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// build32bppDIB encodes a BITMAPINFOHEADER DIB for a w x h image with a
+// fully opaque solid-color XOR plane and an all-zero AND mask, optionally
+// overriding the stored height field to exercise malformed input.
+func build32bppDIB(w, h int, c color.NRGBA, storedHeight int32) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint32(40)) // header size
+	binary.Write(buf, binary.LittleEndian, int32(w))
+	binary.Write(buf, binary.LittleEndian, storedHeight)
+	binary.Write(buf, binary.LittleEndian, uint16(1))  // planes
+	binary.Write(buf, binary.LittleEndian, uint16(32)) // bit count
+	binary.Write(buf, binary.LittleEndian, uint32(0))  // compression
+	binary.Write(buf, binary.LittleEndian, uint32(0))  // image size
+	binary.Write(buf, binary.LittleEndian, int32(0))
+	binary.Write(buf, binary.LittleEndian, int32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+
+	rowSize := ((w*32 + 31) / 32) * 4
+	for y := 0; y < h; y++ {
+		row := make([]byte, rowSize)
+		for x := 0; x < w; x++ {
+			row[x*4+0] = c.B
+			row[x*4+1] = c.G
+			row[x*4+2] = c.R
+			row[x*4+3] = c.A
+		}
+		buf.Write(row)
+	}
+
+	andRowSize := ((w + 31) / 32) * 4
+	buf.Write(make([]byte, andRowSize*h))
+	return buf.Bytes()
+}
+
+func TestDecodeAllRoundTripsOpaqueDIB(t *testing.T) {
+	want := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	payload := build32bppDIB(4, 4, want, 8)
+	data := buildICO(1, []rawEntry{{width: 4, height: 4, payload: payload}})
+
+	images, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("got %d images, want 1", len(images))
+	}
+	img := images[0]
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 4 {
+		t.Fatalf("bounds = %v, want 4x4", b)
+	}
+	r, g, b, a := img.At(0, 0).RGBA()
+	got := color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+	if got != want {
+		t.Fatalf("pixel = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeAllRoundTripsPNGEntry(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	src.Set(1, 1, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	pngBuf := &bytes.Buffer{}
+	if err := png.Encode(pngBuf, src); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	data := buildICO(1, []rawEntry{{width: 3, height: 3, payload: pngBuf.Bytes()}})
+
+	images, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("got %d images, want 1", len(images))
+	}
+	r, g, b, _ := images[0].At(1, 1).RGBA()
+	if uint8(r>>8) != 200 || uint8(g>>8) != 100 || uint8(b>>8) != 50 {
+		t.Fatalf("pixel = (%d,%d,%d), want (200,100,50)", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestParseIco(t *testing.T) {
+	payload := build32bppDIB(2, 2, color.NRGBA{A: 255}, 4)
+	data := buildICO(1, []rawEntry{{width: 2, height: 2, payload: payload}})
+
+	entries, err := ParseIco(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseIco: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Width != 2 || entries[0].Height != 2 {
+		t.Fatalf("entries = %+v, want one 2x2 entry", entries)
+	}
+}
+
+func TestDecodeAllRejectsNegativeHeight(t *testing.T) {
+	// A DIB that claims a negative biHeight must be rejected, not used to
+	// slice the payload: xorSize would go negative and panic.
+	payload := build32bppDIB(10, 10, color.NRGBA{A: 255}, -20)
+	data := buildICO(1, []rawEntry{{width: 10, height: 10, payload: payload}})
+
+	if _, err := DecodeAll(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Fatalf("err = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestDecodeAllRejectsZeroHeight(t *testing.T) {
+	payload := build32bppDIB(10, 10, color.NRGBA{A: 255}, 0)
+	data := buildICO(1, []rawEntry{{width: 10, height: 10, payload: payload}})
+
+	if _, err := DecodeAll(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Fatalf("err = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestDecodeAllRejectsTruncatedPayload(t *testing.T) {
+	payload := build32bppDIB(10, 10, color.NRGBA{A: 255}, 20)
+	// Cut into the XOR plane itself, not just the trailing (optional) AND
+	// mask, so this must fail the bounds check in decodeDIB.
+	truncated := payload[:40+10] // header plus a few XOR bytes only
+	data := buildICO(1, []rawEntry{{width: 10, height: 10, payload: truncated}})
+
+	if _, err := DecodeAll(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Fatalf("err = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestParseIcoRejectsBadHeader(t *testing.T) {
+	if _, err := ParseIco(bytes.NewReader([]byte{1, 2, 3})); err != ErrInvalidFormat {
+		t.Fatalf("err = %v, want ErrInvalidFormat", err)
+	}
+}
@@ -0,0 +1,13 @@
+package iconconv
+
+// Importing this package registers decoders for every source format the
+// tool accepts, so callers can simply use image.Decode on their input.
+import (
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
@@ -0,0 +1,267 @@
+// Package iconconv converts a decoded image into the bytes of a Windows
+// icon (.ico) or cursor (.cur) file at a set of sizes, so the conversion
+// logic can be embedded by other Go programs instead of only being
+// reachable through the CLI.
+package iconconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/nfnt/resize"
+)
+
+// DefaultSizes is the icon size set used when Options.Sizes is empty.
+var DefaultSizes = []int{16, 32, 48, 64, 128, 256}
+
+// DefaultPNGThreshold is the size at or above which entries embed a PNG
+// stream instead of a classic BITMAPINFOHEADER DIB, used when
+// Options.PNGThreshold is zero.
+const DefaultPNGThreshold = 256
+
+// Options controls how Convert renders its output.
+type Options struct {
+	// Sizes lists the square dimensions to render, e.g. {16, 32, 256}.
+	// Defaults to DefaultSizes when nil.
+	Sizes []int
+	// Filter is the resampling filter used to resize the source image.
+	// Its zero value is resize.NearestNeighbor; use DefaultOptions for
+	// resize.Lanczos3 instead.
+	Filter resize.InterpolationFunction
+	// PNGThreshold is the size at or above which an entry is stored as a
+	// PNG stream rather than a BMP+mask. Defaults to DefaultPNGThreshold
+	// when zero; set to a value larger than the largest size to disable.
+	PNGThreshold int
+	// CUR selects cursor (.cur) output instead of icon (.ico) output.
+	CUR bool
+	// Hotspot is the cursor hotspot, in the coordinate space of the source
+	// image. It is scaled proportionally per resized frame. Ignored unless
+	// CUR is set.
+	Hotspot image.Point
+}
+
+// DefaultOptions returns the tool's historical defaults: the classic
+// 16..256 size set, Lanczos3 resampling, and a 256px PNG threshold.
+func DefaultOptions() Options {
+	return Options{
+		Sizes:        DefaultSizes,
+		Filter:       resize.Lanczos3,
+		PNGThreshold: DefaultPNGThreshold,
+	}
+}
+
+// withDefaults fills in Sizes and PNGThreshold when left zero. Filter is
+// left alone: its zero value, NearestNeighbor, is itself a meaningful
+// choice, so callers who want Lanczos3 should start from DefaultOptions.
+func (o Options) withDefaults() Options {
+	if len(o.Sizes) == 0 {
+		o.Sizes = DefaultSizes
+	}
+	if o.PNGThreshold == 0 {
+		o.PNGThreshold = DefaultPNGThreshold
+	}
+	return o
+}
+
+// Convert resizes src to every size in opts.Sizes and returns the encoded
+// bytes of the resulting ICO, or CUR file when opts.CUR is set.
+func Convert(src image.Image, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+
+	bounds := src.Bounds()
+	if opts.CUR && (bounds.Dx() == 0 || bounds.Dy() == 0) {
+		return nil, fmt.Errorf("iconconv: source image has zero size")
+	}
+
+	entries := make([]dirEntry, 0, len(opts.Sizes))
+	for _, size := range opts.Sizes {
+		resized := resize.Resize(uint(size), uint(size), src, opts.Filter)
+		blob, err := encodeEntryBlob(resized, size, opts.PNGThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("iconconv: failed to encode %dx%d entry: %w", size, size, err)
+		}
+		entry := dirEntry{width: size, height: size, blob: blob}
+		if opts.CUR {
+			entry.field1 = uint16(clamp(opts.Hotspot.X*size/bounds.Dx(), 0, size-1))
+			entry.field2 = uint16(clamp(opts.Hotspot.Y*size/bounds.Dy(), 0, size-1))
+		} else {
+			entry.field1, entry.field2 = 1, 32 // Planes, Bit Count
+		}
+		entries = append(entries, entry)
+	}
+
+	iconType := uint16(1)
+	if opts.CUR {
+		iconType = 2
+	}
+	buf := &bytes.Buffer{}
+	if err := writeIconDirectory(buf, iconType, entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteICO encodes img at each of sizes into a Windows icon file and writes
+// it to w. It is a thin wrapper around Convert using DefaultOptions with
+// Sizes overridden, kept for callers that only need the ICO path.
+func WriteICO(w io.Writer, img image.Image, sizes []int) error {
+	opts := DefaultOptions()
+	opts.Sizes = sizes
+	data, err := Convert(img, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteCUR encodes img at each of sizes into a Windows cursor file and
+// writes it to w, mirroring WriteICO. hotspot is given in the coordinate
+// space of the source image and is scaled proportionally for each resized
+// frame, then clamped to stay within that frame.
+func WriteCUR(w io.Writer, img image.Image, sizes []int, hotspot image.Point) error {
+	opts := DefaultOptions()
+	opts.Sizes = sizes
+	opts.CUR = true
+	opts.Hotspot = hotspot
+	data, err := Convert(img, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// dirEntry holds one ICONDIRENTRY/CURSORDIRENTRY worth of data while an ICO
+// or CUR file is being assembled. Planes/BitCount (ICO) and the hotspot X/Y
+// coordinates (CUR) share the same on-disk layout, just a different meaning.
+type dirEntry struct {
+	width, height  int
+	field1, field2 uint16
+	blob           []byte
+}
+
+// writeIconDirectory writes an ICONDIR/CURSORDIR header (iconType 1 for ICO,
+// 2 for CUR) followed by its directory entries and image payloads.
+func writeIconDirectory(w *bytes.Buffer, iconType uint16, entries []dirEntry) error {
+	w.Write([]byte{0, 0}) // Reserved
+	binary.Write(w, binary.LittleEndian, iconType)
+	binary.Write(w, binary.LittleEndian, uint16(len(entries)))
+
+	dir := &bytes.Buffer{}
+	imageData := &bytes.Buffer{}
+	offset := 6 + 16*len(entries)
+	for _, e := range entries {
+		width, height := e.width, e.height
+		if width >= 256 {
+			width = 0
+		}
+		if height >= 256 {
+			height = 0
+		}
+		dir.WriteByte(byte(width))
+		dir.WriteByte(byte(height))
+		dir.WriteByte(0) // Color Count
+		dir.WriteByte(0) // Reserved
+		binary.Write(dir, binary.LittleEndian, e.field1)
+		binary.Write(dir, binary.LittleEndian, e.field2)
+		binary.Write(dir, binary.LittleEndian, uint32(len(e.blob)))
+		binary.Write(dir, binary.LittleEndian, uint32(offset))
+		imageData.Write(e.blob)
+		offset += len(e.blob)
+	}
+
+	w.Write(dir.Bytes())
+	w.Write(imageData.Bytes())
+	return nil
+}
+
+// EncodeBMPEntry encodes img as the classic BITMAPINFOHEADER DIB with its
+// AND mask appended, the same XOR+mask payload used by BMP-based ICO/CUR
+// entries and by RT_ICON resources in a Windows .res/.syso file.
+func EncodeBMPEntry(img image.Image) ([]byte, error) {
+	bmp, andMask, err := encodeBMPWithTransparency(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode BMP: %v", err)
+	}
+	return append(bmp, andMask...), nil
+}
+
+// encodeEntryBlob encodes img as a single directory-entry payload: a PNG
+// stream when size is at or above pngThreshold (the Vista+ format, with no
+// "0 means 256" ambiguity and far smaller on disk), otherwise the classic
+// BITMAPINFOHEADER DIB with its AND mask appended.
+func encodeEntryBlob(img image.Image, size, pngThreshold int) ([]byte, error) {
+	if size >= pngThreshold {
+		buf := &bytes.Buffer{}
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := enc.Encode(buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode PNG entry: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+	bmp, andMask, err := encodeBMPWithTransparency(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode BMP: %v", err)
+	}
+	return append(bmp, andMask...), nil
+}
+
+func encodeBMPWithTransparency(img image.Image) ([]byte, []byte, error) {
+	width := img.Bounds().Dx()
+	height := img.Bounds().Dy()
+	headerSize := 40
+	imageSize := width * height * 4
+
+	// BITMAPINFOHEADER (an ICO entry carries no BMP file header)
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint32(headerSize)) // Header size
+	binary.Write(buf, binary.LittleEndian, int32(width))
+	binary.Write(buf, binary.LittleEndian, int32(height*2)) // Height includes both image and mask
+	binary.Write(buf, binary.LittleEndian, uint16(1))       // Planes
+	binary.Write(buf, binary.LittleEndian, uint16(32))      // Bits per pixel
+	binary.Write(buf, binary.LittleEndian, uint32(0))       // Compression
+	binary.Write(buf, binary.LittleEndian, uint32(imageSize))
+	binary.Write(buf, binary.LittleEndian, int32(0)) // Pixels per meter (X)
+	binary.Write(buf, binary.LittleEndian, int32(0)) // Pixels per meter (Y)
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+
+	// AND mask: 1 bpp, rows padded to a 4-byte boundary, bottom-up like the
+	// pixel data. Bit set (1) means transparent.
+	maskRowSize := ((width + 31) / 32) * 4
+	mask := make([]byte, maskRowSize*height)
+
+	// Pixel Data (BGRA format), bottom-up
+	row := 0
+	for y := img.Bounds().Max.Y - 1; y >= img.Bounds().Min.Y; y-- {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			buf.WriteByte(byte(b >> 8))
+			buf.WriteByte(byte(g >> 8))
+			buf.WriteByte(byte(r >> 8))
+			buf.WriteByte(byte(a >> 8))
+			if a>>8 < 128 {
+				col := x - img.Bounds().Min.X
+				mask[row*maskRowSize+col/8] |= 1 << uint(7-col%8)
+			}
+		}
+		row++
+	}
+
+	return buf.Bytes(), mask, nil
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
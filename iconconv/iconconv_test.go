@@ -0,0 +1,123 @@
+package iconconv
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Insolent-M1nx/Icon_Converter/ico"
+)
+
+// solidImage returns a size x size NRGBA image filled with fill, except for
+// a single marker pixel at (0,0) set to mark, so a round-tripped decode can
+// be checked against a specific, recognizable pixel.
+func solidImage(size int, fill, mark color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, fill)
+		}
+	}
+	img.SetNRGBA(0, 0, mark)
+	return img
+}
+
+func TestConvertICORoundTrip(t *testing.T) {
+	mark := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	src := solidImage(8, color.NRGBA{R: 200, G: 200, B: 200, A: 255}, mark)
+
+	data, err := Convert(src, Options{Sizes: []int{8}})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	images, err := ico.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ico.DecodeAll: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("got %d images, want 1", len(images))
+	}
+	if b := images[0].Bounds(); b.Dx() != 8 || b.Dy() != 8 {
+		t.Fatalf("bounds = %v, want 8x8", b)
+	}
+	r, g, b, a := images[0].At(0, 0).RGBA()
+	got := color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+	if got != mark {
+		t.Fatalf("pixel(0,0) = %+v, want %+v", got, mark)
+	}
+}
+
+func TestConvertCURRoundTripCarriesHotspot(t *testing.T) {
+	src := solidImage(16, color.NRGBA{A: 255}, color.NRGBA{A: 255})
+	hotspot := image.Point{X: 4, Y: 12}
+
+	data, err := Convert(src, Options{Sizes: []int{16}, CUR: true, Hotspot: hotspot})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	entries, err := ico.ParseIco(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ico.ParseIco: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	// For CUR entries, ico.DirEntry's Planes/BitCount fields hold the raw
+	// hotspot X/Y words rather than their ICO meaning.
+	if int(entries[0].Planes) != hotspot.X || int(entries[0].BitCount) != hotspot.Y {
+		t.Fatalf("hotspot = (%d,%d), want (%d,%d)", entries[0].Planes, entries[0].BitCount, hotspot.X, hotspot.Y)
+	}
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func TestConvertPNGThreshold(t *testing.T) {
+	src := solidImage(300, color.NRGBA{A: 255}, color.NRGBA{A: 255})
+
+	data, err := Convert(src, Options{Sizes: []int{8, 300}, PNGThreshold: 256})
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+
+	entries, err := ico.ParseIco(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ico.ParseIco: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	payloadAt := func(e ico.DirEntry) []byte {
+		start := e.ImageOffset
+		return data[start : start+e.BytesInRes]
+	}
+	if bytes.HasPrefix(payloadAt(entries[0]), pngSignature) {
+		t.Fatalf("8x8 entry (below threshold) encoded as PNG")
+	}
+	if !bytes.HasPrefix(payloadAt(entries[1]), pngSignature) {
+		t.Fatalf("300x300 entry (at/above threshold) not encoded as PNG")
+	}
+}
+
+func TestWriteICOAndWriteCUR(t *testing.T) {
+	src := solidImage(8, color.NRGBA{A: 255}, color.NRGBA{A: 255})
+
+	var icoBuf bytes.Buffer
+	if err := WriteICO(&icoBuf, src, []int{8}); err != nil {
+		t.Fatalf("WriteICO: %v", err)
+	}
+	if _, err := ico.DecodeAll(bytes.NewReader(icoBuf.Bytes())); err != nil {
+		t.Fatalf("decode WriteICO output: %v", err)
+	}
+
+	var curBuf bytes.Buffer
+	if err := WriteCUR(&curBuf, src, []int{8}, image.Point{X: 2, Y: 2}); err != nil {
+		t.Fatalf("WriteCUR: %v", err)
+	}
+	if _, err := ico.DecodeAll(bytes.NewReader(curBuf.Bytes())); err != nil {
+		t.Fatalf("decode WriteCUR output: %v", err)
+	}
+}
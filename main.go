@@ -1,166 +1,242 @@
-package main
-
-import (
-	"bytes"
-	"encoding/binary"
-	"flag"
-	"fmt"
-	"image"
-	"image/png"
-	"os"
-	"path/filepath"
-
-	"github.com/nfnt/resize"
-)
-
-func main() {
-	// Define flags for input and output directories
-	inputDir := flag.String("f", "", "Directory containing .png files to convert to .ico")
-	outputDir := flag.String("o", "", "Output directory for .ico files")
-	flag.Parse()
-
-	// Validate input and output directories
-	if *inputDir == "" || *outputDir == "" {
-		fmt.Println("Usage: go run main.go -f input_dir -o output_dir")
-		return
-	}
-
-	// Ensure output directory exists
-	if _, err := os.Stat(*outputDir); os.IsNotExist(err) {
-		err := os.MkdirAll(*outputDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create output directory %s: %v\n", *outputDir, err)
-			return
-		}
-	}
-
-	// Get all PNG files in the input directory
-	files, err := filepath.Glob(filepath.Join(*inputDir, "*.png"))
-	if err != nil || len(files) == 0 {
-		fmt.Println("No PNG files found in the input directory.")
-		return
-	}
-
-	// Process each file
-	for _, inputPath := range files {
-		fmt.Printf("Processing %s...\n", inputPath)
-		outputPath := filepath.Join(*outputDir, filepath.Base(inputPath[:len(inputPath)-len(filepath.Ext(inputPath))]+".ico"))
-		if err := createICO(inputPath, outputPath); err != nil {
-			fmt.Printf("Failed to create ICO for %s: %v\n", inputPath, err)
-		} else {
-			fmt.Printf("Created %s\n", outputPath)
-		}
-	}
-
-	fmt.Println("Conversion completed.")
-}
-
-func createICO(inputPath, outputPath string) error {
-	// Open the input image
-	file, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
-	}
-	defer file.Close()
-
-	// Decode the image
-	img, err := png.Decode(file)
-	if err != nil {
-		return fmt.Errorf("failed to decode PNG: %v", err)
-	}
-
-	// Resize the image to multiple sizes
-	sizes := []int{16, 32, 48, 64, 128, 256}
-	var images []image.Image
-	for _, size := range sizes {
-		images = append(images, resize.Resize(uint(size), uint(size), img, resize.Lanczos3))
-	}
-
-	// Create ICO file
-	outFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
-	}
-	defer outFile.Close()
-
-	// Write ICO header
-	buf := &bytes.Buffer{}
-	buf.Write([]byte{0, 0, 1, 0}) // Reserved + Type
-	binary.Write(buf, binary.LittleEndian, uint16(len(images)))
-
-	// Write directory entries
-	imageData := &bytes.Buffer{}
-	offset := 6 + (16 * len(images))
-	for _, img := range images {
-		bmp, andMask, err := encodeBMPWithTransparency(img)
-		if err != nil {
-			return fmt.Errorf("failed to encode BMP: %v", err)
-		}
-		width := img.Bounds().Dx()
-		height := img.Bounds().Dy()
-		if width >= 256 {
-			width = 0
-		}
-		if height >= 256 {
-			height = 0
-		}
-		buf.WriteByte(byte(width))
-		buf.WriteByte(byte(height))
-		buf.WriteByte(0) // Color Count
-		buf.WriteByte(0) // Reserved
-		binary.Write(buf, binary.LittleEndian, uint16(1))      // Planes
-		binary.Write(buf, binary.LittleEndian, uint16(32))     // Bit Count
-		binary.Write(buf, binary.LittleEndian, uint32(len(bmp)+len(andMask)))
-		binary.Write(buf, binary.LittleEndian, uint32(offset))
-		imageData.Write(bmp)
-		imageData.Write(andMask)
-		offset += len(bmp) + len(andMask)
-	}
-
-	// Write header and image data to the file
-	outFile.Write(buf.Bytes())
-	outFile.Write(imageData.Bytes())
-	return nil
-}
-
-func encodeBMPWithTransparency(img image.Image) ([]byte, []byte, error) {
-	width := img.Bounds().Dx()
-	height := img.Bounds().Dy()
-	headerSize := 40
-	imageSize := width * height * 4
-
-	// File Header
-	buf := &bytes.Buffer{}
-	binary.Write(buf, binary.LittleEndian, uint32(headerSize)) // Header size
-	binary.Write(buf, binary.LittleEndian, int32(width))
-	binary.Write(buf, binary.LittleEndian, int32(height*2)) // Height includes both image and mask
-	binary.Write(buf, binary.LittleEndian, uint16(1))       // Planes
-	binary.Write(buf, binary.LittleEndian, uint16(32))      // Bits per pixel
-	binary.Write(buf, binary.LittleEndian, uint32(0))       // Compression
-	binary.Write(buf, binary.LittleEndian, uint32(imageSize))
-	binary.Write(buf, binary.LittleEndian, int32(0)) // Pixels per meter (X)
-	binary.Write(buf, binary.LittleEndian, int32(0)) // Pixels per meter (Y)
-	binary.Write(buf, binary.LittleEndian, uint32(0))
-	binary.Write(buf, binary.LittleEndian, uint32(0))
-
-	// Pixel Data (BGRA format)
-	for y := img.Bounds().Max.Y - 1; y >= img.Bounds().Min.Y; y-- {
-		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			buf.WriteByte(byte(b >> 8))
-			buf.WriteByte(byte(g >> 8))
-			buf.WriteByte(byte(r >> 8))
-			buf.WriteByte(byte(a >> 8))
-		}
-	}
-
-	// AND Mask (Transparency)
-	mask := &bytes.Buffer{}
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			mask.WriteByte(0x00) // Fully transparent mask
-		}
-	}
-
-	return buf.Bytes(), mask.Bytes(), nil
-}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nfnt/resize"
+
+	"github.com/Insolent-M1nx/Icon_Converter/iconconv"
+	"github.com/Insolent-M1nx/Icon_Converter/syso"
+)
+
+var filters = map[string]resize.InterpolationFunction{
+	"nearestneighbor": resize.NearestNeighbor,
+	"bilinear":        resize.Bilinear,
+	"bicubic":         resize.Bicubic,
+	"lanczos2":        resize.Lanczos2,
+	"lanczos3":        resize.Lanczos3,
+}
+
+func main() {
+	inputDir := flag.String("f", "", "Directory containing source images to convert")
+	outputDir := flag.String("o", "", "Output directory for .ico/.cur files")
+	ext := flag.String("ext", "png", "Comma-separated list of source file extensions to scan")
+	filterName := flag.String("filter", "lanczos3", "Resampling filter: nearestneighbor, bilinear, bicubic, lanczos2, lanczos3")
+	sizesFlag := flag.String("sizes", "16,32,48,64,128,256", "Comma-separated list of output sizes")
+	cur := flag.Bool("cur", false, "Write Windows cursor (.cur) files instead of icons")
+	hotspotFlag := flag.String("hotspot", "", "Cursor hotspot as X,Y (applies to every file, -cur only)")
+	hotspotsFile := flag.String("hotspots", "", "JSON sidecar mapping basename -> {\"x\":X,\"y\":Y} (-cur only)")
+	pngThresholdFlag := flag.Int("png-threshold", iconconv.DefaultPNGThreshold, "Sizes at or above this embed a PNG stream instead of a BMP+mask entry")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "Number of files to convert in parallel")
+	sysoOut := flag.String("syso", "", "Write a Windows .syso resource for the first source image, for go build GOOS=windows to embed as the binary's icon")
+	archFlag := flag.String("arch", "amd64", "Target architecture for -syso: 386, amd64, arm64")
+	flag.Parse()
+
+	if *inputDir == "" || *outputDir == "" {
+		fmt.Println("Usage: go run . -f input_dir -o output_dir")
+		return
+	}
+
+	filter, ok := filters[strings.ToLower(*filterName)]
+	if !ok {
+		fmt.Printf("Unknown -filter %q\n", *filterName)
+		return
+	}
+
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		fmt.Printf("Invalid -sizes value %q: %v\n", *sizesFlag, err)
+		return
+	}
+
+	var hotspots map[string]image.Point
+	if *hotspotsFile != "" {
+		hotspots, err = loadHotspots(*hotspotsFile)
+		if err != nil {
+			fmt.Printf("Failed to load hotspots file %s: %v\n", *hotspotsFile, err)
+			return
+		}
+	}
+	defaultHotspot := image.Point{}
+	if *hotspotFlag != "" {
+		pt, err := parseHotspot(*hotspotFlag)
+		if err != nil {
+			fmt.Printf("Invalid -hotspot value %q: %v\n", *hotspotFlag, err)
+			return
+		}
+		defaultHotspot = pt
+	}
+
+	if _, err := os.Stat(*outputDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			fmt.Printf("Failed to create output directory %s: %v\n", *outputDir, err)
+			return
+		}
+	}
+
+	files, err := findSourceFiles(*inputDir, *ext)
+	if err != nil || len(files) == 0 {
+		fmt.Println("No source files found in the input directory.")
+		return
+	}
+
+	opts := iconconv.DefaultOptions()
+	opts.Sizes = sizes
+	opts.Filter = filter
+	opts.PNGThreshold = *pngThresholdFlag
+	opts.CUR = *cur
+
+	if *sysoOut != "" {
+		if err := writeSyso(files[0], *sysoOut, sizes, *archFlag); err != nil {
+			fmt.Printf("Failed to write %s: %v\n", *sysoOut, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s from %s\n", *sysoOut, files[0])
+	}
+
+	if *jobs <= 0 {
+		*jobs = 1
+	}
+	os.Exit(runPipeline(files, *outputDir, *cur, hotspots, defaultHotspot, opts, *jobs))
+}
+
+// writeSyso decodes inputPath and writes a Windows .syso resource file for
+// it at outputPath. Only the first source file is used: a .syso embeds a
+// single application icon, unlike the per-file .ico/.cur batch output.
+func writeSyso(inputPath, outputPath string, sizes []int, arch string) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer outFile.Close()
+
+	return syso.Write(outFile, img, sizes, arch)
+}
+
+// findSourceFiles scans dir for files whose extension appears in the
+// comma-separated extList, returning a deduplicated, sorted list of paths.
+func findSourceFiles(dir, extList string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, e := range strings.Split(extList, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, "*."+e))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// convertFile decodes inputPath (in any format iconconv registers a decoder
+// for) and writes the converted icon/cursor to outputPath.
+func convertFile(inputPath, outputPath string, opts iconconv.Options) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	data, err := iconconv.Convert(img, opts)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// parseSizes parses a comma-separated list of positive integers.
+func parseSizes(s string) ([]int, error) {
+	parts := strings.Split(s, ",")
+	sizes := make([]int, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q: %v", p, err)
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("size %d must be positive", n)
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no sizes given")
+	}
+	return sizes, nil
+}
+
+// parseHotspot parses a "X,Y" flag value into an image.Point.
+func parseHotspot(s string) (image.Point, error) {
+	parts := strings.SplitN(s, ",", 2)
+	if len(parts) != 2 {
+		return image.Point{}, fmt.Errorf("expected format X,Y")
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return image.Point{}, fmt.Errorf("invalid X: %v", err)
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return image.Point{}, fmt.Errorf("invalid Y: %v", err)
+	}
+	return image.Point{X: x, Y: y}, nil
+}
+
+// loadHotspots reads a JSON sidecar mapping basename -> {"x":X,"y":Y}.
+func loadHotspots(path string) (map[string]image.Point, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	hotspots := make(map[string]image.Point, len(raw))
+	for name, pt := range raw {
+		hotspots[name] = image.Point{X: pt.X, Y: pt.Y}
+	}
+	return hotspots, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"sync"
+
+	"github.com/Insolent-M1nx/Icon_Converter/iconconv"
+)
+
+// jobResult is what a worker reports back for one converted file.
+type jobResult struct {
+	path       string
+	outputPath string
+	err        error
+}
+
+// runPipeline fans the given files out over a pool of jobs workers, each
+// converting one file at a time, and reports progress as results come back.
+// It returns the process exit code: 0 if every file converted, 1 otherwise.
+func runPipeline(files []string, outputDir string, cur bool, hotspots map[string]image.Point, defaultHotspot image.Point, baseOpts iconconv.Options, jobs int) int {
+	jobsCh := make(chan string, len(files))
+	for _, f := range files {
+		jobsCh <- f
+	}
+	close(jobsCh)
+
+	resultsCh := make(chan jobResult, len(files))
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go convertWorker(jobsCh, resultsCh, &wg, outputDir, cur, hotspots, defaultHotspot, baseOpts)
+	}
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	return reportResults(resultsCh, len(files))
+}
+
+// convertWorker pulls input paths off jobsCh and converts each one,
+// publishing a jobResult for every file it handles.
+func convertWorker(jobsCh <-chan string, resultsCh chan<- jobResult, wg *sync.WaitGroup, outputDir string, cur bool, hotspots map[string]image.Point, defaultHotspot image.Point, baseOpts iconconv.Options) {
+	defer wg.Done()
+	for inputPath := range jobsCh {
+		base := filepath.Base(inputPath[:len(inputPath)-len(filepath.Ext(inputPath))])
+
+		opts := baseOpts
+		outExt := ".ico"
+		if cur {
+			outExt = ".cur"
+			opts.Hotspot = defaultHotspot
+			if pt, ok := hotspots[base]; ok {
+				opts.Hotspot = pt
+			}
+		}
+		outputPath := filepath.Join(outputDir, base+outExt)
+
+		err := convertFile(inputPath, outputPath, opts)
+		resultsCh <- jobResult{path: inputPath, outputPath: outputPath, err: err}
+	}
+}
+
+// reportResults is the pipeline's sole stdout writer: since it is the only
+// goroutine draining resultsCh, progress lines and the final summary never
+// interleave without an explicit mutex.
+func reportResults(resultsCh <-chan jobResult, total int) int {
+	done := 0
+	var failures []jobResult
+	for r := range resultsCh {
+		done++
+		if r.err != nil {
+			fmt.Printf("[%d/%d] %s -> FAILED: %v\n", done, total, r.path, r.err)
+			failures = append(failures, r)
+		} else {
+			fmt.Printf("[%d/%d] %s -> %s\n", done, total, r.path, r.outputPath)
+		}
+	}
+
+	fmt.Println("Conversion completed.")
+	if len(failures) == 0 {
+		return 0
+	}
+	fmt.Printf("%d/%d files failed:\n", len(failures), total)
+	for _, f := range failures {
+		fmt.Printf("  %s: %v\n", f.path, f.err)
+	}
+	return 1
+}
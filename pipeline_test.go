@@ -0,0 +1,60 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/Insolent-M1nx/Icon_Converter/iconconv"
+)
+
+// benchSourceDir writes n tiny PNGs into a temp directory and returns their
+// paths, for benchmarking the conversion pipeline over a realistic batch
+// size without shipping hundreds of fixture files in the repo.
+func benchSourceDir(b *testing.B, n int) []string {
+	b.Helper()
+	dir := b.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 7), B: 128, A: uint8(200 + x)})
+		}
+	}
+
+	paths := make([]string, n)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, "src"+strconv.Itoa(i)+".png")
+		f, err := os.Create(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			f.Close()
+			b.Fatal(err)
+		}
+		f.Close()
+		paths[i] = path
+	}
+	return paths
+}
+
+// BenchmarkRunPipeline measures the worker-pool conversion path over 500
+// source images, matching the directory size the pipeline is expected to
+// handle in practice.
+func BenchmarkRunPipeline(b *testing.B) {
+	files := benchSourceDir(b, 500)
+	outDir := b.TempDir()
+	opts := iconconv.DefaultOptions()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if code := runPipeline(files, outDir, false, nil, image.Point{}, opts, runtime.NumCPU()); code != 0 {
+			b.Fatalf("runPipeline returned exit code %d", code)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package syso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	coffFileHeaderSize    = 20
+	coffSectionHeaderSize = 40
+	coffRelocationSize    = 10
+	coffSymbolSize        = 18
+)
+
+// writeCOFF wraps rsrc (the already-built .rsrc section contents) in a
+// minimal single-section COFF object file: a file header, one section
+// header for .rsrc, the section data, its relocations, a symbol table
+// holding just the .rsrc section symbol, and an empty string table. The
+// linker resolves relocOffsets against that symbol to turn the
+// section-relative placeholders baked into rsrc into real RVAs.
+func writeCOFF(w io.Writer, a archInfo, rsrc []byte, relocOffsets []int) error {
+	rawDataOff := coffFileHeaderSize + coffSectionHeaderSize
+	relocOff := rawDataOff + len(rsrc)
+	symbolTableOff := relocOff + len(relocOffsets)*coffRelocationSize
+
+	buf := &bytes.Buffer{}
+
+	// COFF file header
+	binary.Write(buf, binary.LittleEndian, a.machine)
+	binary.Write(buf, binary.LittleEndian, uint16(1)) // NumberOfSections
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // TimeDateStamp
+	binary.Write(buf, binary.LittleEndian, uint32(symbolTableOff))
+	binary.Write(buf, binary.LittleEndian, uint32(2)) // NumberOfSymbols: 1 symbol + 1 aux
+	binary.Write(buf, binary.LittleEndian, uint16(0)) // SizeOfOptionalHeader
+	binary.Write(buf, binary.LittleEndian, uint16(0)) // Characteristics
+
+	// Section header: .rsrc
+	var name [8]byte
+	copy(name[:], ".rsrc")
+	buf.Write(name[:])
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // VirtualSize
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // VirtualAddress
+	binary.Write(buf, binary.LittleEndian, uint32(len(rsrc)))
+	binary.Write(buf, binary.LittleEndian, uint32(rawDataOff))
+	binary.Write(buf, binary.LittleEndian, uint32(relocOff))
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // PointerToLinenumbers
+	binary.Write(buf, binary.LittleEndian, uint16(len(relocOffsets)))
+	binary.Write(buf, binary.LittleEndian, uint16(0)) // NumberOfLinenumbers
+	binary.Write(buf, binary.LittleEndian, uint32(imageSCNCntInitializedData|imageSCNMemRead))
+
+	// Raw section data
+	buf.Write(rsrc)
+
+	// Relocations: each data-entry offset gets patched to a real RVA by
+	// adding the linked .rsrc section's address to the section-relative
+	// placeholder already stored there.
+	const rscSymbolIndex = 0
+	for _, off := range relocOffsets {
+		binary.Write(buf, binary.LittleEndian, uint32(off))
+		binary.Write(buf, binary.LittleEndian, uint32(rscSymbolIndex))
+		binary.Write(buf, binary.LittleEndian, a.addr32NBReloc)
+	}
+
+	// Symbol table: one STATIC section symbol for .rsrc, with its
+	// mandatory section-definition auxiliary record.
+	copy(name[:], ".rsrc")
+	buf.Write(name[:])
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // Value
+	binary.Write(buf, binary.LittleEndian, int16(1))  // SectionNumber (1-based)
+	binary.Write(buf, binary.LittleEndian, uint16(0)) // Type
+	buf.WriteByte(3)                                  // StorageClass: IMAGE_SYM_CLASS_STATIC
+	buf.WriteByte(1)                                  // NumberOfAuxSymbols
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(rsrc))) // aux: Length
+	binary.Write(buf, binary.LittleEndian, uint16(len(relocOffsets)))
+	binary.Write(buf, binary.LittleEndian, uint16(0)) // NumberOfLinenumbers
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // CheckSum
+	binary.Write(buf, binary.LittleEndian, int16(0))  // Number (COMDAT selection target)
+	buf.WriteByte(0)                                  // Selection
+	buf.Write(make([]byte, 3))                        // Unused
+
+	// String table: just the 4-byte size field, no long names in use.
+	binary.Write(buf, binary.LittleEndian, uint32(4))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,199 @@
+// Package syso builds a minimal Windows COFF object file (.syso) carrying
+// an RT_GROUP_ICON/RT_ICON resource pair, so a generated icon can be dropped
+// next to a main.go and picked up automatically by `go build GOOS=windows`.
+package syso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/nfnt/resize"
+
+	"github.com/Insolent-M1nx/Icon_Converter/iconconv"
+)
+
+const (
+	rtIcon        = 3
+	rtGroupIcon   = 14
+	langEnglishUS = 0x0409
+	groupIconID   = 1 // the single GRPICONDIR we emit always has resource ID 1
+
+	imageSCNCntInitializedData = 0x00000040
+	imageSCNMemRead            = 0x40000000
+)
+
+// archInfo is everything that differs between target machine architectures:
+// the COFF Machine constant and the "address, no base" relocation type used
+// to patch resource-directory offsets into real RVAs at link time.
+type archInfo struct {
+	machine       uint16
+	addr32NBReloc uint16
+}
+
+var archs = map[string]archInfo{
+	"386":   {machine: 0x014c, addr32NBReloc: 0x0007}, // IMAGE_REL_I386_DIR32NB
+	"amd64": {machine: 0x8664, addr32NBReloc: 0x0003}, // IMAGE_REL_AMD64_ADDR32NB
+	"arm64": {machine: 0xaa64, addr32NBReloc: 0x0002}, // IMAGE_REL_ARM64_ADDR32NB
+}
+
+type iconImage struct {
+	width, height int
+	data          []byte
+}
+
+// dirEntry is one entry of an IMAGE_RESOURCE_DIRECTORY: an ID, whether it
+// points at another directory or at an IMAGE_RESOURCE_DATA_ENTRY, and the
+// section-relative offset of whichever it points at.
+type dirEntry struct {
+	id       uint32
+	subdir   bool
+	atOffset int
+}
+
+// Write resizes img to each of sizes and writes a .syso object file
+// containing one RT_ICON resource per size plus a single RT_GROUP_ICON
+// tying them together, for the given target architecture ("386", "amd64"
+// or "arm64").
+func Write(w io.Writer, img image.Image, sizes []int, arch string) error {
+	a, ok := archs[arch]
+	if !ok {
+		return fmt.Errorf("syso: unsupported arch %q", arch)
+	}
+
+	images := make([]iconImage, 0, len(sizes))
+	for _, size := range sizes {
+		resized := resize.Resize(uint(size), uint(size), img, resize.Lanczos3)
+		data, err := iconconv.EncodeBMPEntry(resized)
+		if err != nil {
+			return fmt.Errorf("syso: failed to encode %dx%d icon: %w", size, size, err)
+		}
+		images = append(images, iconImage{width: size, height: size, data: data})
+	}
+
+	rsrc, relocOffsets := buildResourceSection(images, buildGroupIconDir(images))
+	return writeCOFF(w, a, rsrc, relocOffsets)
+}
+
+// buildGroupIconDir assembles the GRPICONDIR + GRPICONDIRENTRY records that
+// make up the RT_GROUP_ICON resource's data, referencing RT_ICON ids 1..N.
+func buildGroupIconDir(images []iconImage) []byte {
+	group := &bytes.Buffer{}
+	binary.Write(group, binary.LittleEndian, uint16(0)) // Reserved
+	binary.Write(group, binary.LittleEndian, uint16(1)) // Type: icon
+	binary.Write(group, binary.LittleEndian, uint16(len(images)))
+	for i, im := range images {
+		width, height := im.width, im.height
+		if width >= 256 {
+			width = 0
+		}
+		if height >= 256 {
+			height = 0
+		}
+		group.WriteByte(byte(width))
+		group.WriteByte(byte(height))
+		group.WriteByte(0)                                   // Color Count
+		group.WriteByte(0)                                   // Reserved
+		binary.Write(group, binary.LittleEndian, uint16(1))  // Planes
+		binary.Write(group, binary.LittleEndian, uint16(32)) // Bit Count
+		binary.Write(group, binary.LittleEndian, uint32(len(im.data)))
+		binary.Write(group, binary.LittleEndian, uint16(i+1)) // nID
+	}
+	return group.Bytes()
+}
+
+// buildResourceSection lays out the three-level (type -> name -> language)
+// resource directory tree for RT_ICON{1..N} and RT_GROUP_ICON{1}, followed
+// by their IMAGE_RESOURCE_DATA_ENTRY records and raw payloads. It returns
+// the assembled .rsrc section contents and the list of section offsets
+// whose OffsetToData field needs an ADDR32NB relocation against the
+// section, since those must hold a real RVA once linked.
+func buildResourceSection(images []iconImage, groupData []byte) ([]byte, []int) {
+	n := len(images)
+	const dirHeaderSize = 16
+	const dirEntrySize = 8
+	const dataEntrySize = 16
+
+	// Every block's size is known up front, so offsets can be resolved in
+	// one pass before any bytes are written.
+	offset := 0
+	alloc := func(size int) int {
+		off := offset
+		offset += size
+		return off
+	}
+
+	typeDirOff := alloc(dirHeaderSize + 2*dirEntrySize)
+	iconNameDirOff := alloc(dirHeaderSize + n*dirEntrySize)
+	groupNameDirOff := alloc(dirHeaderSize + 1*dirEntrySize)
+
+	iconLangDirOff := make([]int, n)
+	for i := range images {
+		iconLangDirOff[i] = alloc(dirHeaderSize + 1*dirEntrySize)
+	}
+	groupLangDirOff := alloc(dirHeaderSize + 1*dirEntrySize)
+
+	iconDataEntryOff := make([]int, n)
+	for i := range images {
+		iconDataEntryOff[i] = alloc(dataEntrySize)
+	}
+	groupDataEntryOff := alloc(dataEntrySize)
+
+	iconDataOff := make([]int, n)
+	for i, im := range images {
+		iconDataOff[i] = alloc(len(im.data))
+	}
+	groupDataOff := alloc(len(groupData))
+
+	buf := make([]byte, offset)
+	putDir := func(off int, entries []dirEntry) {
+		binary.LittleEndian.PutUint16(buf[off+12:], 0)                    // NumberOfNamedEntries
+		binary.LittleEndian.PutUint16(buf[off+14:], uint16(len(entries))) // NumberOfIdEntries
+		for i, e := range entries {
+			entryOff := off + dirHeaderSize + i*dirEntrySize
+			binary.LittleEndian.PutUint32(buf[entryOff:], e.id)
+			v := uint32(e.atOffset)
+			if e.subdir {
+				v |= 0x80000000
+			}
+			binary.LittleEndian.PutUint32(buf[entryOff+4:], v)
+		}
+	}
+	putDataEntry := func(off, dataOff, size int) {
+		binary.LittleEndian.PutUint32(buf[off:], uint32(dataOff)) // OffsetToData, relocated at link time
+		binary.LittleEndian.PutUint32(buf[off+4:], uint32(size))
+		binary.LittleEndian.PutUint32(buf[off+8:], 0)  // CodePage
+		binary.LittleEndian.PutUint32(buf[off+12:], 0) // Reserved
+	}
+
+	putDir(typeDirOff, []dirEntry{
+		{id: rtIcon, subdir: true, atOffset: iconNameDirOff},
+		{id: rtGroupIcon, subdir: true, atOffset: groupNameDirOff},
+	})
+
+	iconNames := make([]dirEntry, n)
+	for i := range images {
+		iconNames[i] = dirEntry{id: uint32(i + 1), subdir: true, atOffset: iconLangDirOff[i]}
+	}
+	putDir(iconNameDirOff, iconNames)
+	putDir(groupNameDirOff, []dirEntry{{id: groupIconID, subdir: true, atOffset: groupLangDirOff}})
+
+	for i := range images {
+		putDir(iconLangDirOff[i], []dirEntry{{id: langEnglishUS, atOffset: iconDataEntryOff[i]}})
+	}
+	putDir(groupLangDirOff, []dirEntry{{id: langEnglishUS, atOffset: groupDataEntryOff}})
+
+	var relocOffsets []int
+	for i, im := range images {
+		putDataEntry(iconDataEntryOff[i], iconDataOff[i], len(im.data))
+		relocOffsets = append(relocOffsets, iconDataEntryOff[i])
+		copy(buf[iconDataOff[i]:], im.data)
+	}
+	putDataEntry(groupDataEntryOff, groupDataOff, len(groupData))
+	relocOffsets = append(relocOffsets, groupDataEntryOff)
+	copy(buf[groupDataOff:], groupData)
+
+	return buf, relocOffsets
+}
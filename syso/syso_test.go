@@ -0,0 +1,156 @@
+package syso
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/nfnt/resize"
+
+	"github.com/Insolent-M1nx/Icon_Converter/iconconv"
+)
+
+// coffFile is the subset of a parsed COFF object this test needs to check:
+// the file header's Machine field and the raw bytes of its single section.
+type coffFile struct {
+	machine     uint16
+	sectionData []byte
+}
+
+// parseCOFF reads back the minimal single-.rsrc-section object writeCOFF
+// produces, without pulling in a full object-file library.
+func parseCOFF(t *testing.T, data []byte) coffFile {
+	t.Helper()
+	if len(data) < coffFileHeaderSize+coffSectionHeaderSize {
+		t.Fatalf("object file too short: %d bytes", len(data))
+	}
+	machine := binary.LittleEndian.Uint16(data[0:2])
+	numSections := binary.LittleEndian.Uint16(data[2:4])
+	if numSections != 1 {
+		t.Fatalf("NumberOfSections = %d, want 1", numSections)
+	}
+
+	sh := data[coffFileHeaderSize : coffFileHeaderSize+coffSectionHeaderSize]
+	name := bytes.TrimRight(sh[0:8], "\x00")
+	if string(name) != ".rsrc" {
+		t.Fatalf("section name = %q, want .rsrc", name)
+	}
+	sizeOfRawData := binary.LittleEndian.Uint32(sh[16:20])
+	ptrToRawData := binary.LittleEndian.Uint32(sh[20:24])
+
+	end := int(ptrToRawData) + int(sizeOfRawData)
+	if end > len(data) {
+		t.Fatalf("section data [%d:%d] exceeds file length %d", ptrToRawData, end, len(data))
+	}
+	return coffFile{machine: machine, sectionData: data[ptrToRawData:end]}
+}
+
+// rsrcDirEntry mirrors one IMAGE_RESOURCE_DIRECTORY_ENTRY.
+type rsrcDirEntry struct {
+	id     uint32
+	subdir bool
+	at     int
+}
+
+func readDirEntries(rsrc []byte, off int) []rsrcDirEntry {
+	numID := int(binary.LittleEndian.Uint16(rsrc[off+14 : off+16]))
+	entries := make([]rsrcDirEntry, numID)
+	for i := 0; i < numID; i++ {
+		eOff := off + 16 + i*8
+		id := binary.LittleEndian.Uint32(rsrc[eOff : eOff+4])
+		v := binary.LittleEndian.Uint32(rsrc[eOff+4 : eOff+8])
+		entries[i] = rsrcDirEntry{id: id, subdir: v&0x80000000 != 0, at: int(v &^ 0x80000000)}
+	}
+	return entries
+}
+
+// findEntry returns the entry with the given id, failing the test if absent.
+func findEntry(t *testing.T, entries []rsrcDirEntry, id uint32) rsrcDirEntry {
+	t.Helper()
+	for _, e := range entries {
+		if e.id == id {
+			return e
+		}
+	}
+	t.Fatalf("no resource directory entry with id %d among %+v", id, entries)
+	return rsrcDirEntry{}
+}
+
+// dataEntryBytes reads the IMAGE_RESOURCE_DATA_ENTRY at off and returns the
+// raw payload it points at (still section-relative, unrelocated, which is
+// exactly what the relocations list patches at link time).
+func dataEntryBytes(rsrc []byte, off int) []byte {
+	dataOff := binary.LittleEndian.Uint32(rsrc[off : off+4])
+	size := binary.LittleEndian.Uint32(rsrc[off+4 : off+8])
+	return rsrc[dataOff : dataOff+size]
+}
+
+func TestWriteProducesWalkableResourceTree(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: 100, A: 255})
+		}
+	}
+	sizes := []int{16, 32}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, img, sizes, "amd64"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	obj := parseCOFF(t, buf.Bytes())
+	if obj.machine != archs["amd64"].machine {
+		t.Fatalf("Machine = %#x, want %#x", obj.machine, archs["amd64"].machine)
+	}
+
+	typeDir := readDirEntries(obj.sectionData, 0)
+	iconType := findEntry(t, typeDir, rtIcon)
+	groupType := findEntry(t, typeDir, rtGroupIcon)
+	if !iconType.subdir || !groupType.subdir {
+		t.Fatalf("RT_ICON/RT_GROUP_ICON type entries must point at subdirectories")
+	}
+
+	iconNames := readDirEntries(obj.sectionData, iconType.at)
+	if len(iconNames) != len(sizes) {
+		t.Fatalf("got %d RT_ICON name entries, want %d", len(iconNames), len(sizes))
+	}
+	for i, size := range sizes {
+		nameEntry := findEntry(t, iconNames, uint32(i+1))
+		langEntries := readDirEntries(obj.sectionData, nameEntry.at)
+		lang := findEntry(t, langEntries, langEnglishUS)
+		if lang.subdir {
+			t.Fatalf("RT_ICON id %d language entry must point at a data entry", i+1)
+		}
+		got := dataEntryBytes(obj.sectionData, lang.at)
+
+		resized := resize.Resize(uint(size), uint(size), img, resize.Lanczos3)
+		want, err := iconconv.EncodeBMPEntry(resized)
+		if err != nil {
+			t.Fatalf("EncodeBMPEntry: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("RT_ICON id %d payload mismatch: got %d bytes, want %d bytes", i+1, len(got), len(want))
+		}
+	}
+
+	groupNames := readDirEntries(obj.sectionData, groupType.at)
+	groupName := findEntry(t, groupNames, groupIconID)
+	groupLang := readDirEntries(obj.sectionData, groupName.at)
+	lang := findEntry(t, groupLang, langEnglishUS)
+	groupData := dataEntryBytes(obj.sectionData, lang.at)
+
+	if got := binary.LittleEndian.Uint16(groupData[4:6]); int(got) != len(sizes) {
+		t.Fatalf("GRPICONDIR count = %d, want %d", got, len(sizes))
+	}
+}
+
+func TestWriteRejectsUnknownArch(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	if err := Write(&buf, img, []int{16}, "sparc"); err == nil {
+		t.Fatalf("Write with unknown arch did not return an error")
+	}
+}